@@ -0,0 +1,36 @@
+package proxy
+
+import "sync"
+
+// BufferPool is a sync.Pool-backed cache of copy buffers, shared by
+// FastReverseProxy and proxy/fast so both avoid allocating a fresh copy
+// buffer for every proxied request. It replaces the `var buf []byte` that
+// copyResponse used to pass into io.CopyBuffer, which was always nil and
+// forced a fresh 32 KiB allocation on every call.
+type BufferPool struct {
+	pool sync.Pool
+}
+
+// NewBufferPool creates a BufferPool that hands out buffers of size bytes,
+// falling back to defaultCopyBufferSize when size is unset.
+func NewBufferPool(size int) *BufferPool {
+	if size <= 0 {
+		size = defaultCopyBufferSize
+	}
+	return &BufferPool{
+		pool: sync.Pool{
+			New: func() interface{} {
+				b := make([]byte, size)
+				return &b
+			},
+		},
+	}
+}
+
+func (b *BufferPool) Get() []byte {
+	return *b.pool.Get().(*[]byte)
+}
+
+func (b *BufferPool) Put(buf []byte) {
+	b.pool.Put(&buf)
+}
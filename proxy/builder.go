@@ -0,0 +1,13 @@
+package proxy
+
+import "net/http"
+
+// Builder resolves a route and proxies a request to the chosen backend. It
+// is the seam between the router's handler chain and the concrete proxy
+// implementation, letting operators choose FastReverseProxy (stdlib
+// net/http, shared Transports from a RoundTripperManager) or proxy/fast
+// (pooled, keep-alive connections written to directly) without changing
+// anything upstream of the proxy.
+type Builder interface {
+	ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc)
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CircuitBreakerSettings configures when an endpoint's circuit trips open
+// on error rate and how long it stays open before a half-open probe.
+type CircuitBreakerSettings struct {
+	ErrorRateThreshold float64
+	Window             time.Duration
+	CooldownPeriod     time.Duration
+}
+
+func (s CircuitBreakerSettings) withDefaults() CircuitBreakerSettings {
+	if s.ErrorRateThreshold <= 0 {
+		s.ErrorRateThreshold = 0.5
+	}
+	if s.Window <= 0 {
+		s.Window = 10 * time.Second
+	}
+	if s.CooldownPeriod <= 0 {
+		s.CooldownPeriod = 5 * time.Second
+	}
+	return s
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker trips open (à la oxy/cbreaker) once the error rate measured
+// over a rolling Window crosses ErrorRateThreshold, short-circuiting
+// requests to a fallback response until a single half-open probe succeeds.
+type circuitBreaker struct {
+	settings CircuitBreakerSettings
+
+	mu          sync.Mutex
+	state       breakerState
+	openedAt    time.Time
+	windowStart time.Time
+	total       int
+	failures    int
+}
+
+func newCircuitBreaker(settings CircuitBreakerSettings) *circuitBreaker {
+	return &circuitBreaker{
+		settings:    settings.withDefaults(),
+		windowStart: time.Now(),
+	}
+}
+
+// Allow reports whether a request should be let through, advancing an open
+// breaker to half-open once CooldownPeriod has elapsed. Only the caller
+// that wins the open->half-open transition is let through as the probe;
+// since mu serializes Allow/Report, every other concurrent caller either
+// still sees breakerOpen (cooldown not elapsed) or sees breakerHalfOpen
+// (a probe is already in flight) and is refused until that probe's
+// Report() resolves the state one way or the other.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.settings.CooldownPeriod {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// Report records the outcome of a request that Allow let through.
+func (b *circuitBreaker) Report(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerHalfOpen {
+		if success {
+			b.reset()
+		} else {
+			b.trip()
+		}
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(b.windowStart) > b.settings.Window {
+		b.windowStart = now
+		b.total = 0
+		b.failures = 0
+	}
+	b.total++
+	if !success {
+		b.failures++
+	}
+	if b.total >= 10 && float64(b.failures)/float64(b.total) >= b.settings.ErrorRateThreshold {
+		b.trip()
+	}
+}
+
+func (b *circuitBreaker) trip() {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+}
+
+func (b *circuitBreaker) reset() {
+	b.state = breakerClosed
+	b.total = 0
+	b.failures = 0
+}
+
+// perEndpointCircuitBreakers lazily creates and caches one circuitBreaker
+// per backend address, the same way outlierEjector tracks failures per
+// address: a single shared RoundTripper serves every endpoint behind a
+// given (scheme, TLS config), and a breaker keyed any coarser than that
+// would let one failing app's backend trip the breaker for every other
+// app's traffic sharing that transport.
+//
+// Addresses not seen in staleAfter are swept out, the same way
+// outlierEjector ages out stale addresses: CF backends churn constantly, and
+// without this the breakers map would grow by one entry per address ever
+// seen over the life of a long-running router process.
+type perEndpointCircuitBreakers struct {
+	settings      CircuitBreakerSettings
+	staleAfter    time.Duration
+	sweepInterval time.Duration
+
+	mu        sync.Mutex
+	breakers  map[string]*circuitBreaker
+	lastSeen  map[string]time.Time
+	lastSwept time.Time
+}
+
+func newPerEndpointCircuitBreakers(settings CircuitBreakerSettings) *perEndpointCircuitBreakers {
+	settings = settings.withDefaults()
+	return &perEndpointCircuitBreakers{
+		settings:      settings,
+		staleAfter:    settings.Window * staleAddrMultiple,
+		sweepInterval: settings.Window * staleAddrMultiple,
+		breakers:      make(map[string]*circuitBreaker),
+		lastSeen:      make(map[string]time.Time),
+	}
+}
+
+func (p *perEndpointCircuitBreakers) forAddr(addr string) *circuitBreaker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.lastSeen[addr] = now
+	p.sweepLocked(now)
+
+	b, ok := p.breakers[addr]
+	if !ok {
+		b = newCircuitBreaker(p.settings)
+		p.breakers[addr] = b
+	}
+	return b
+}
+
+// sweepLocked drops breakers for addresses that haven't been seen in
+// staleAfter. It only actually scans the map once per sweepInterval; mu
+// must already be held.
+func (p *perEndpointCircuitBreakers) sweepLocked(now time.Time) {
+	if now.Sub(p.lastSwept) < p.sweepInterval {
+		return
+	}
+	p.lastSwept = now
+	for addr, seen := range p.lastSeen {
+		if now.Sub(seen) >= p.staleAfter {
+			delete(p.lastSeen, addr)
+			delete(p.breakers, addr)
+		}
+	}
+}
+
+// circuitBreakingRoundTripper short-circuits to a 503 fallback response
+// once the breaker for the request's target address has tripped open,
+// instead of dialing a backend that's already known to be failing.
+type circuitBreakingRoundTripper struct {
+	next     http.RoundTripper
+	breakers *perEndpointCircuitBreakers
+}
+
+func (t *circuitBreakingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	breaker := t.breakers.forAddr(req.URL.Host)
+	if !breaker.Allow() {
+		return fallbackResponse(req, http.StatusServiceUnavailable), nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	breaker.Report(err == nil && resp != nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+func fallbackResponse(req *http.Request, statusCode int) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}
+}
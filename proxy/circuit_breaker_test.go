@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCircuitBreakerTripsOnErrorRate verifies a breaker only trips once both
+// the minimum sample size and ErrorRateThreshold are crossed within Window.
+func TestCircuitBreakerTripsOnErrorRate(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerSettings{ErrorRateThreshold: 0.5, Window: time.Minute, CooldownPeriod: time.Minute})
+
+	for i := 0; i < 9; i++ {
+		if !b.Allow() {
+			t.Fatalf("expected breaker closed before trip threshold, attempt %d", i)
+		}
+		b.Report(false)
+	}
+	if !b.Allow() {
+		t.Fatalf("expected breaker still closed below the 10-sample minimum")
+	}
+	b.Report(false)
+
+	if b.Allow() {
+		t.Errorf("expected breaker open after crossing ErrorRateThreshold over >=10 samples")
+	}
+}
+
+// TestCircuitBreakerHalfOpenProbeSucceedsResets verifies a single successful
+// probe after CooldownPeriod closes the breaker and clears its counters.
+func TestCircuitBreakerHalfOpenProbeSucceedsResets(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerSettings{ErrorRateThreshold: 0.5, Window: time.Minute, CooldownPeriod: time.Millisecond})
+	b.trip()
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatalf("expected breaker to allow a half-open probe after CooldownPeriod")
+	}
+	b.Report(true)
+
+	if b.state != breakerClosed {
+		t.Errorf("expected breaker closed after successful probe, got state %d", b.state)
+	}
+	if !b.Allow() {
+		t.Errorf("expected breaker to stay closed and allow requests")
+	}
+}
+
+// TestCircuitBreakerHalfOpenProbeFailsTripsAgain verifies a failed probe
+// re-opens the breaker rather than leaving it half-open indefinitely.
+func TestCircuitBreakerHalfOpenProbeFailsTripsAgain(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerSettings{ErrorRateThreshold: 0.5, Window: time.Minute, CooldownPeriod: time.Millisecond})
+	b.trip()
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatalf("expected breaker to allow a half-open probe after CooldownPeriod")
+	}
+	b.Report(false)
+
+	if b.state != breakerOpen {
+		t.Errorf("expected breaker re-opened after a failed probe, got state %d", b.state)
+	}
+	if b.Allow() {
+		t.Errorf("expected breaker to stay open immediately after re-tripping")
+	}
+}
+
+// TestCircuitBreakerHalfOpenAllowsOnlyOneProbe verifies that once a probe
+// is let through after CooldownPeriod, further concurrent Allow() calls
+// are refused until that probe's Report() resolves the state -- otherwise
+// a flood of requests arriving right as cooldown expires would all reach
+// the still-possibly-failing backend instead of just one probe.
+func TestCircuitBreakerHalfOpenAllowsOnlyOneProbe(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerSettings{ErrorRateThreshold: 0.5, Window: time.Minute, CooldownPeriod: time.Millisecond})
+	b.trip()
+	time.Sleep(2 * time.Millisecond)
+
+	if !b.Allow() {
+		t.Fatalf("expected breaker to allow the first half-open probe after CooldownPeriod")
+	}
+	for i := 0; i < 5; i++ {
+		if b.Allow() {
+			t.Errorf("attempt %d: expected breaker to refuse concurrent callers while a probe is in flight", i)
+		}
+	}
+
+	b.Report(true)
+	if !b.Allow() {
+		t.Errorf("expected breaker closed and allowing requests after the probe resolved")
+	}
+}
+
+// TestPerEndpointCircuitBreakersSweepsStaleAddrs verifies breakers for
+// addresses not seen in staleAfter are evicted, so a long-running router
+// doesn't leak one breaker per address ever seen as CF backends churn.
+func TestPerEndpointCircuitBreakersSweepsStaleAddrs(t *testing.T) {
+	p := newPerEndpointCircuitBreakers(CircuitBreakerSettings{Window: time.Millisecond})
+	p.staleAfter = time.Millisecond
+	p.sweepInterval = time.Millisecond
+
+	p.forAddr("10.0.0.1:8080")
+	if _, ok := p.breakers["10.0.0.1:8080"]; !ok {
+		t.Fatalf("expected breaker for 10.0.0.1:8080 to be created")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	p.forAddr("10.0.0.2:8080")
+
+	if _, ok := p.breakers["10.0.0.1:8080"]; ok {
+		t.Errorf("expected stale breaker for 10.0.0.1:8080 to be swept")
+	}
+	if _, ok := p.breakers["10.0.0.2:8080"]; !ok {
+		t.Errorf("expected breaker for 10.0.0.2:8080 to survive the sweep that created it")
+	}
+}
@@ -0,0 +1,150 @@
+// Package fast provides an alternative to proxy.FastReverseProxy that
+// forwards requests over a pool of long-lived, keep-alive backend
+// connections instead of dialing a fresh http.Transport per request.
+package fast
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"code.cloudfoundry.org/gorouter/proxy"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// Config holds the tunables for a Builder's connection pools and copy
+// buffers. Zero values fall back to the defaults applied by
+// PoolConfig.withDefaults and proxy.NewBufferPool.
+type Config struct {
+	PoolConfig
+	BufferSize int
+}
+
+// Builder manufactures an http.Handler per endpoint, each backed by its own
+// pool of keep-alive connections keyed on CanonicalAddr() and TLS config.
+type Builder struct {
+	cfg  Config
+	bufs *proxy.BufferPool
+
+	mu    sync.Mutex
+	pools map[string]*connPool
+}
+
+// NewBuilder creates a Builder using cfg for pool sizing and buffer
+// allocation.
+func NewBuilder(cfg Config) *Builder {
+	return &Builder{
+		cfg:   cfg,
+		bufs:  proxy.NewBufferPool(cfg.BufferSize),
+		pools: make(map[string]*connPool),
+	}
+}
+
+// poolFor returns the connection pool for endpoint, creating one on first
+// use. A plaintext and a TLS connection to the same address are not
+// interchangeable, so the TLS config is folded into the cache key.
+func (b *Builder) poolFor(endpoint *route.Endpoint, tlsConfig *tls.Config) *connPool {
+	key := endpoint.CanonicalAddr()
+	if tlsConfig != nil {
+		key += fmt.Sprintf("|tls|%p", tlsConfig)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if p, ok := b.pools[key]; ok {
+		return p
+	}
+	p := newConnPool(endpoint.CanonicalAddr(), tlsConfig, b.cfg.PoolConfig)
+	b.pools[key] = p
+	return p
+}
+
+// HandlerFor returns an http.Handler that proxies every request it receives
+// to endpoint over a pooled keep-alive connection.
+func (b *Builder) HandlerFor(endpoint *route.Endpoint, tlsConfig *tls.Config) http.Handler {
+	return &endpointProxy{pool: b.poolFor(endpoint, tlsConfig), bufs: b.bufs}
+}
+
+// ServeEndpoint proxies req to endpoint over a pooled keep-alive connection,
+// the same as the http.Handler returned by HandlerFor, but reports whether
+// the attempt completed rather than failing before a response was written.
+// Callers that need to know this -- e.g. to decide whether to continue a
+// handler chain -- should use this instead of HandlerFor.
+func (b *Builder) ServeEndpoint(rw http.ResponseWriter, req *http.Request, endpoint *route.Endpoint, tlsConfig *tls.Config) bool {
+	ep := &endpointProxy{pool: b.poolFor(endpoint, tlsConfig), bufs: b.bufs}
+	return ep.serve(rw, req)
+}
+
+// Close tears down every pool the Builder has created, closing all idle
+// connections. Call it once during graceful shutdown.
+func (b *Builder) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range b.pools {
+		p.Close()
+	}
+}
+
+// endpointProxy writes a request directly onto a pooled connection and
+// streams the response back, skipping the per-request allocations
+// http.Transport would otherwise perform.
+type endpointProxy struct {
+	pool *connPool
+	bufs *proxy.BufferPool
+}
+
+func (e *endpointProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	e.serve(rw, req)
+}
+
+// serve is ServeHTTP's implementation, reporting whether the request was
+// proxied through to a written response (true) or failed before one was
+// ever written (false).
+func (e *endpointProxy) serve(rw http.ResponseWriter, req *http.Request) bool {
+	conn, err := e.pool.Get()
+	if err != nil {
+		http.Error(rw, "failed to connect to backend", http.StatusBadGateway)
+		return false
+	}
+
+	bw := bufio.NewWriterSize(conn, e.pool.cfg.WriteBufferSize)
+	if err := req.Write(bw); err != nil {
+		conn.Close()
+		http.Error(rw, "failed to write request to backend", http.StatusBadGateway)
+		return false
+	}
+	if err := bw.Flush(); err != nil {
+		conn.Close()
+		http.Error(rw, "failed to write request to backend", http.StatusBadGateway)
+		return false
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReaderSize(conn, e.pool.cfg.ReadBufferSize), req)
+	if err != nil {
+		conn.Close()
+		http.Error(rw, "failed to read response from backend", http.StatusBadGateway)
+		return false
+	}
+	defer resp.Body.Close()
+
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			rw.Header().Add(k, v)
+		}
+	}
+	rw.WriteHeader(resp.StatusCode)
+
+	if _, err := copyBody(rw, resp.Body, e.bufs); err != nil {
+		conn.Close()
+		return true
+	}
+
+	if resp.Close {
+		conn.Close()
+		return true
+	}
+	e.pool.Put(conn)
+	return true
+}
@@ -0,0 +1,21 @@
+package fast
+
+import (
+	"io"
+
+	"code.cloudfoundry.org/gorouter/proxy"
+)
+
+// copyBody streams src to dst using a buffer drawn from the pool.
+//
+// This used to special-case the src/dst being *net.TCPConn and prefer
+// (*net.TCPConn).ReadFrom to ride the kernel's splice(2) path. In practice
+// that path never engaged: dst here is always an http.ResponseWriter, never
+// a raw *net.TCPConn, and src is the body returned by http.ReadResponse,
+// which reads through a bufio.Reader rather than being a *net.TCPConn
+// itself. Rather than keep dead code around, the special case was dropped.
+func copyBody(dst io.Writer, src io.Reader, bufs *proxy.BufferPool) (int64, error) {
+	buf := bufs.Get()
+	defer bufs.Put(buf)
+	return io.CopyBuffer(dst, src, buf)
+}
@@ -0,0 +1,157 @@
+package fast
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// PoolConfig controls the sizing and timeouts of a single endpoint's
+// connection pool.
+type PoolConfig struct {
+	MinIdleConns    int
+	MaxIdleConns    int
+	IdleTimeout     time.Duration
+	DialTimeout     time.Duration
+	ReadBufferSize  int
+	WriteBufferSize int
+}
+
+func (c PoolConfig) withDefaults() PoolConfig {
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = 8
+	}
+	if c.IdleTimeout <= 0 {
+		c.IdleTimeout = 90 * time.Second
+	}
+	if c.DialTimeout <= 0 {
+		c.DialTimeout = 5 * time.Second
+	}
+	if c.ReadBufferSize <= 0 {
+		c.ReadBufferSize = 4096
+	}
+	if c.WriteBufferSize <= 0 {
+		c.WriteBufferSize = 4096
+	}
+	if c.MinIdleConns > c.MaxIdleConns {
+		c.MinIdleConns = c.MaxIdleConns
+	}
+	return c
+}
+
+// connPool maintains a bounded set of long-lived, keep-alive connections to
+// a single backend endpoint. Callers key pools on CanonicalAddr() plus TLS
+// config, so a pool only ever holds interchangeable connections. Idle
+// connections are handed out by Get and returned by Put; Get lazily dials
+// when the pool is empty, and Put closes connections once the pool is full
+// rather than leaking them.
+type connPool struct {
+	addr      string
+	tlsConfig *tls.Config
+	dialer    *net.Dialer
+	cfg       PoolConfig
+
+	idle   chan *pooledConn
+	stopCh chan struct{}
+}
+
+type pooledConn struct {
+	net.Conn
+	returnedAt time.Time
+}
+
+func newConnPool(addr string, tlsConfig *tls.Config, cfg PoolConfig) *connPool {
+	cfg = cfg.withDefaults()
+	p := &connPool{
+		addr:      addr,
+		tlsConfig: tlsConfig,
+		dialer:    &net.Dialer{Timeout: cfg.DialTimeout},
+		cfg:       cfg,
+		idle:      make(chan *pooledConn, cfg.MaxIdleConns),
+		stopCh:    make(chan struct{}),
+	}
+	p.refill()
+	if p.cfg.MinIdleConns > 0 {
+		go p.maintainMinIdle()
+	}
+	return p
+}
+
+// refill dials enough connections to bring the pool up to MinIdleConns.
+// Dial failures are ignored here -- Get still dials lazily on every
+// subsequent call, so a backend that isn't reachable right now just means
+// refill caught nothing this round.
+func (p *connPool) refill() {
+	for i := len(p.idle); i < p.cfg.MinIdleConns; i++ {
+		conn, err := p.dial()
+		if err != nil {
+			return
+		}
+		p.Put(conn)
+	}
+}
+
+// maintainMinIdle periodically tops the pool back up to MinIdleConns, since
+// Get and the IdleTimeout check in it otherwise only ever shrink the idle
+// set, never replenish it.
+func (p *connPool) maintainMinIdle() {
+	ticker := time.NewTicker(p.cfg.IdleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.refill()
+		case <-p.stopCh:
+			return
+		}
+	}
+}
+
+// Get returns an idle connection if one is available and still within
+// IdleTimeout, otherwise it lazily dials a new one.
+func (p *connPool) Get() (net.Conn, error) {
+	for {
+		select {
+		case pc := <-p.idle:
+			if p.cfg.IdleTimeout > 0 && time.Since(pc.returnedAt) > p.cfg.IdleTimeout {
+				pc.Conn.Close()
+				continue
+			}
+			return pc.Conn, nil
+		default:
+			return p.dial()
+		}
+	}
+}
+
+func (p *connPool) dial() (net.Conn, error) {
+	if p.tlsConfig != nil {
+		return tls.DialWithDialer(p.dialer, "tcp", p.addr, p.tlsConfig)
+	}
+	return p.dialer.Dial("tcp", p.addr)
+}
+
+// Put returns conn to the pool for reuse. If the pool is already at
+// MaxIdleConns, conn is closed instead of being discarded without cleanup.
+func (p *connPool) Put(conn net.Conn) {
+	pc := &pooledConn{Conn: conn, returnedAt: time.Now()}
+	select {
+	case p.idle <- pc:
+	default:
+		conn.Close()
+	}
+}
+
+// Close stops min-idle maintenance and drains and closes every idle
+// connection currently held by the pool.
+func (p *connPool) Close() {
+	close(p.stopCh)
+	for {
+		select {
+		case pc := <-p.idle:
+			pc.Conn.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package fast
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// listenAndAccept starts a listener that accepts and holds open every
+// connection made to it, so tests can dial a real backend without needing
+// to speak any particular protocol over it.
+func listenAndAccept(t *testing.T) (addr string, stop func()) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				<-done
+				conn.Close()
+			}()
+		}
+	}()
+	return ln.Addr().String(), func() {
+		close(done)
+		ln.Close()
+	}
+}
+
+// TestConnPoolGetReusesPutConnection verifies a connection returned via Put
+// is handed back out by a subsequent Get instead of dialing a new one.
+func TestConnPoolGetReusesPutConnection(t *testing.T) {
+	addr, stop := listenAndAccept(t)
+	defer stop()
+
+	p := newConnPool(addr, nil, PoolConfig{MaxIdleConns: 1})
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put(conn)
+
+	got, err := p.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != conn {
+		t.Errorf("expected Get to return the connection just Put back, got a different one")
+	}
+}
+
+// TestConnPoolPutClosesOverflowConnections verifies Put closes a connection
+// rather than blocking or leaking it once the pool is already at
+// MaxIdleConns.
+func TestConnPoolPutClosesOverflowConnections(t *testing.T) {
+	addr, stop := listenAndAccept(t)
+	defer stop()
+
+	p := newConnPool(addr, nil, PoolConfig{MaxIdleConns: 1})
+	defer p.Close()
+
+	first, err := p.dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := p.dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	p.Put(first)
+	p.Put(second)
+
+	if _, err := second.Write([]byte("x")); err == nil {
+		t.Errorf("expected the overflow connection to have been closed by Put")
+	}
+}
+
+// TestConnPoolGetDiscardsExpiredIdleConnections verifies an idle connection
+// older than IdleTimeout is closed and replaced with a freshly dialed one
+// rather than handed out stale.
+func TestConnPoolGetDiscardsExpiredIdleConnections(t *testing.T) {
+	addr, stop := listenAndAccept(t)
+	defer stop()
+
+	p := newConnPool(addr, nil, PoolConfig{MaxIdleConns: 1, IdleTimeout: time.Millisecond})
+	defer p.Close()
+
+	stale, err := p.dial()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p.Put(stale)
+	time.Sleep(2 * time.Millisecond)
+
+	fresh, err := p.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fresh == stale {
+		t.Errorf("expected Get to discard the expired idle connection and dial a new one")
+	}
+}
+
+// TestNewConnPoolPreWarmsMinIdleConns verifies MinIdleConns connections are
+// dialed eagerly at construction time, rather than sitting unused as
+// documented config.
+func TestNewConnPoolPreWarmsMinIdleConns(t *testing.T) {
+	addr, stop := listenAndAccept(t)
+	defer stop()
+
+	p := newConnPool(addr, nil, PoolConfig{MinIdleConns: 2, MaxIdleConns: 4})
+	defer p.Close()
+
+	if len(p.idle) != 2 {
+		t.Errorf("expected 2 pre-warmed idle connections, got %d", len(p.idle))
+	}
+}
+
+// TestConnPoolMaintainMinIdleRefillsAfterEviction verifies the background
+// maintainMinIdle loop tops the pool back up once idle connections expire,
+// rather than only ever shrinking the idle set.
+func TestConnPoolMaintainMinIdleRefillsAfterEviction(t *testing.T) {
+	addr, stop := listenAndAccept(t)
+	defer stop()
+
+	p := newConnPool(addr, nil, PoolConfig{MinIdleConns: 1, MaxIdleConns: 1, IdleTimeout: 2 * time.Millisecond})
+	defer p.Close()
+
+	stale := <-p.idle
+	stale.Conn.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(p.idle) > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("expected maintainMinIdle to refill the pool after the idle connection was evicted")
+}
@@ -0,0 +1,92 @@
+package fast
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"code.cloudfoundry.org/gorouter/proxy"
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// ProxyBuilder adapts a Builder to satisfy proxy.Builder, letting operators
+// select the pooled-connection fast path in place of FastReverseProxy
+// through configuration, without anything upstream of the proxy needing to
+// change. It shapes the request the same way FastReverseProxy does --
+// stripping hop-by-hop headers, setting X-Forwarded-For/-Proto, and
+// stamping the routing headers backends rely on -- and continues the
+// handler chain via next on success, exactly like every other
+// proxy.Builder implementation. It trades FastReverseProxy's full feature
+// set -- retries across endpoints, sticky sessions, route services,
+// protocol upgrades -- for a leaner request path over long-lived
+// keep-alive connections, so it's meant to be opted into for deployments
+// that don't need those features.
+type ProxyBuilder struct {
+	registry                 proxy.LookupRegistry
+	defaultLoadBalance       string
+	forceForwardedProtoHttps bool
+	builder                  *Builder
+	tlsConfig                *tls.Config
+}
+
+// NewProxyBuilder creates a ProxyBuilder that looks up routes in registry
+// and dispatches matched requests to handlers vended by builder.
+func NewProxyBuilder(registry proxy.LookupRegistry, defaultLoadBalance string, forceForwardedProtoHttps bool, tlsConfig *tls.Config, builder *Builder) *ProxyBuilder {
+	return &ProxyBuilder{
+		registry:                 registry,
+		defaultLoadBalance:       defaultLoadBalance,
+		forceForwardedProtoHttps: forceForwardedProtoHttps,
+		builder:                  builder,
+		tlsConfig:                tlsConfig,
+	}
+}
+
+// ServeHTTP looks up the pool for req's route, picks the next endpoint per
+// the pool's load-balancing order, and proxies to it over a pooled
+// keep-alive connection, calling next once the response has been written.
+// Unlike FastReverseProxy, a failed attempt is not retried against another
+// endpoint in the pool.
+func (b *ProxyBuilder) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	for _, h := range proxy.HopHeaders {
+		req.Header.Del(h)
+	}
+	proxy.SetupForwardedHeaders(req, b.forceForwardedProtoHttps)
+
+	uri := route.Uri(req.Host + req.URL.EscapedPath())
+	pool := b.registry.Lookup(uri)
+	if pool == nil {
+		http.Error(rw, "requested route does not exist", http.StatusNotFound)
+		return
+	}
+
+	iter := pool.Endpoints(b.defaultLoadBalance, getStickySession(req))
+	endpoint := iter.Next()
+	if endpoint == nil {
+		http.Error(rw, "no endpoints available", http.StatusBadGateway)
+		return
+	}
+
+	iter.PreRequest(endpoint)
+	defer iter.PostRequest(endpoint)
+
+	proxy.SetupRequest(req, endpoint)
+	req.URL.Host = endpoint.CanonicalAddr()
+	req.RequestURI = ""
+
+	if b.builder.ServeEndpoint(rw, req, endpoint, b.tlsConfig) {
+		next(rw, req)
+	}
+}
+
+// getStickySession mirrors FastReverseProxy's own helper: if the request
+// carries a sticky session cookie, the pool's iterator will prefer the
+// endpoint it names when one is still registered.
+func getStickySession(req *http.Request) string {
+	if _, err := req.Cookie(proxy.StickyCookieKey); err != nil {
+		return ""
+	}
+	sticky, err := req.Cookie(proxy.VcapCookieId)
+	if err != nil {
+		return ""
+	}
+	return sticky.Value
+}
@@ -1,6 +1,9 @@
 package proxy
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -38,10 +41,6 @@ var HopHeaders = []string{
 
 var xForwardedForKey = "X-Forwarded-For"
 
-const (
-	maxRetries = 3
-)
-
 // FastReverseProxy is responsible for proxying requests to the backend using
 // fasthttp
 type FastReverseProxy struct {
@@ -56,16 +55,34 @@ type FastReverseProxy struct {
 	secureCookies            bool
 	tlsConfig                *tls.Config
 	endpointTimeout          time.Duration
+	roundTripperManager      *RoundTripperManager
+	retryPolicy              RetryPolicy
+	bodyBufferThreshold      int64
+	bufferPool               *BufferPool
+	flushLatency             time.Duration
 }
 
-// NewFastReverseProxy creates a new FastReverseProxy
+// defaultCopyBufferSize is the default size of buffers drawn from
+// FastReverseProxy's BufferPool when copying a response body to the client.
+const defaultCopyBufferSize = 32 * 1024
+
+// defaultFlushLatency is how often a streamed response is flushed to the
+// client by default.
+const defaultFlushLatency = 50 * time.Millisecond
+
+// NewFastReverseProxy creates a new FastReverseProxy. Optional behavior
+// (retry policy, body-buffering threshold, copy buffer size, flush
+// latency) can be overridden with Options; unset options fall back to
+// DefaultRetryPolicy, defaultBodyBufferThreshold, defaultCopyBufferSize,
+// and defaultFlushLatency.
 func NewFastReverseProxy(registry LookupRegistry, logger lager.Logger,
 	reporter reporter.ProxyReporter, routeServiceConfig *routeservice.RouteServiceConfig,
 	forceForwardedProtoHttps bool,
 	traceKey string, defaultLoadBalance string,
-	ip string, secureCookies bool, tlsConfig *tls.Config, endpointTimeout time.Duration) *FastReverseProxy {
+	ip string, secureCookies bool, tlsConfig *tls.Config, endpointTimeout time.Duration,
+	roundTripperManager *RoundTripperManager, opts ...Option) *FastReverseProxy {
 
-	return &FastReverseProxy{
+	f := &FastReverseProxy{
 		registry:                 registry,
 		logger:                   logger,
 		reporter:                 reporter,
@@ -76,8 +93,19 @@ func NewFastReverseProxy(registry LookupRegistry, logger lager.Logger,
 		defaultLoadBalance:       defaultLoadBalance,
 		tlsConfig:                tlsConfig,
 		endpointTimeout:          endpointTimeout,
-		//		secureCookies:            secureCookies,
+		roundTripperManager:      roundTripperManager,
+		retryPolicy:              DefaultRetryPolicy(),
+		bodyBufferThreshold:      defaultBodyBufferThreshold,
+		bufferPool:               NewBufferPool(defaultCopyBufferSize),
+		flushLatency:             defaultFlushLatency,
+		secureCookies:            secureCookies,
+	}
+
+	for _, opt := range opts {
+		opt(f)
 	}
+
+	return f
 }
 
 func (f *FastReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
@@ -101,38 +129,23 @@ func (f *FastReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request,
 
 	backendReq := req
 
+	// Connection/Upgrade are hop-by-hop headers and get stripped by the
+	// loop below, so capture their values now: the upgrade checks further
+	// down, and the generic upgrade handler itself, both need them.
+	upgradeType := upgradeHeader(req)
+	connectionHeader := req.Header.Get("Connection")
+
 	for _, h := range HopHeaders {
 		backendReq.Header.Del(h)
 	}
 
-	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
-		// If we aren't the first proxy retain prior
-		// X-Forwarded-For information as a comma+space
-		// separated list and fold multiple headers into one.
-		var clientIPKey string
-		clientIPKey = clientIP
-		prior := backendReq.Header.Get(xForwardedForKey)
-		if prior != "" {
-			clientIPKey = fmt.Sprintf("%s, %s", prior, clientIP)
-		}
-		backendReq.Header.Set(xForwardedForKey, clientIPKey)
-	}
+	SetupForwardedHeaders(backendReq, f.forceForwardedProtoHttps)
 
 	if !isProtocolSupported(req) {
 		requestHandler.HandleUnsupportedProtocol()
 		return
 	}
 
-	if f.forceForwardedProtoHttps {
-		backendReq.Header.Set("X-Forwarded-Proto", "https")
-	} else if req.Header.Get("X-Forwarded-Proto") == "" {
-		scheme := "http"
-		if req.TLS != nil {
-			scheme = "https"
-		}
-		backendReq.Header.Set("X-Forwarded-Proto", scheme)
-	}
-
 	requestPath := req.URL.EscapedPath()
 	uri := route.Uri(hostWithoutPort(req) + requestPath)
 	pool := f.registry.Lookup(uri)
@@ -153,16 +166,21 @@ func (f *FastReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request,
 		},
 	}
 
-	if isTcpUpgrade(req) {
+	if isTcpUpgrade(upgradeType) {
 		requestHandler.HandleTcpRequest(iter)
 		return
 	}
 
-	if isWebSocketUpgrade(req) {
+	if isWebSocketUpgrade(upgradeType) {
 		requestHandler.HandleWebSocketRequest(iter)
 		return
 	}
 
+	if isUpgradeRequest(upgradeType, connectionHeader) {
+		f.handleUpgrade(rw, backendReq, iter, accessLog, upgradeType, connectionHeader)
+		return
+	}
+
 	backend := true
 	routeServiceUrl := pool.RouteServiceUrl()
 	// Attempted to use a route service when it is not supported
@@ -210,48 +228,67 @@ func (f *FastReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request,
 		}
 	}
 
+	bodyBuffered, rewindBody, err := bufferRequestBody(backendReq, f.bodyBufferThreshold)
+	if err != nil {
+		requestHandler.HandleBadGateway(err, req)
+		return
+	}
+	// A body that couldn't be buffered is a streaming body: whatever the
+	// first attempt already read from it is gone, so there's nothing safe
+	// to resend on retry regardless of whether the method is idempotent.
+	retriesAllowed := bodyBuffered
+
+	maxRetries := f.retryPolicy.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 1
+	}
+
 	var backendResp *http.Response
 	var endpoint *route.Endpoint
-	var err error
 	for retry := 0; retry < maxRetries; retry++ {
+		if retry > 0 {
+			if !retriesAllowed {
+				break
+			}
+			rewindBody()
+			time.Sleep(f.retryPolicy.Backoff(retry - 1))
+		}
+
 		endpoint, err = selectEndpoint(iter)
 
 		if err != nil {
 			break
 		}
-		setupRequest(backendReq, endpoint)
+		SetupRequest(backendReq, endpoint)
 
 		iter.PreRequest(endpoint)
-		//		var hc fasthttp.HostClient
-		var hc http.Client
-		var netTransport = &http.Transport{
-			Dial: (&net.Dialer{
-				Timeout: 5 * time.Second,
-			}).Dial,
-			TLSHandshakeTimeout: 5 * time.Second,
-			DisableKeepAlives:   true,
-		}
-		hc.Transport = netTransport
 		setupProxyRequest(req, backendReq, false)
 		backendReq.RequestURI = ""
 
 		if backend {
 			backendReq.URL.Host = endpoint.CanonicalAddr()
 		}
-		backendResp, err = hc.Do(backendReq)
+
+		roundTripper := f.roundTripperManager.RoundTripperFor(backendReq.URL.Scheme, f.tlsConfig)
+		backendResp, err = roundTripper.RoundTrip(backendReq)
 
 		iter.PostRequest(endpoint)
 		if err != nil {
 			fmt.Println("HTTP Error:", err.Error())
+			if !f.retryPolicy.RetryableError(err, backendReq.Method) {
+				break
+			}
+			continue
 		}
-		if err == nil {
-			break
-		}
-		if !retryableError(err) {
-			break
+
+		if retriesAllowed && f.retryPolicy.RetryableStatus(backendResp.StatusCode) {
+			backendResp.Body.Close()
+			err = fmt.Errorf("retryable status code %d from endpoint %s", backendResp.StatusCode, endpoint.CanonicalAddr())
+			continue
 		}
 
-		// TODO: Log error timed out connecting to backends
+		err = nil
+		break
 	}
 
 	if err != nil {
@@ -282,6 +319,10 @@ func (f *FastReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request,
 		rw.Header()["Content-Type"] = nil
 	}
 
+	if endpoint != nil {
+		f.setupStickySession(rw, backendResp, endpoint, stickyEndpointId, requestPath)
+	}
+
 	if len(backendResp.Trailer) > 0 {
 		trailerKeys := make([]string, 0, len(backendResp.Trailer))
 		for k := range backendResp.Trailer {
@@ -301,7 +342,7 @@ func (f *FastReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request,
 		}
 	}
 
-	f.copyResponse(rw, backendResp.Body)
+	f.copyResponse(rw, backendResp.Body, backendResp)
 	backendResp.Body.Close()
 
 	for k, vv := range backendResp.Trailer {
@@ -314,29 +355,162 @@ func (f *FastReverseProxy) ServeHTTP(rw http.ResponseWriter, req *http.Request,
 
 }
 
-// why are we failing to convert the obj
-func retryableError(err error) bool {
-	netErrString := err.Error()
-	return strings.Contains(netErrString, "dial")
-}
+// handleUpgrade services HTTP/1.1 protocol upgrades that aren't websocket or
+// tcp, mirroring the pattern stdlib's httputil.ReverseProxy uses: hijack the
+// client connection, perform the upgrade handshake against the selected
+// endpoint, and bridge the two raw byte streams, half-closing each direction
+// as its source finishes, until both directions have closed.
+// This covers SPDY, HTTP/2 cleartext upgrades, gRPC-over-h2c, and any other
+// protocol a backend is willing to speak over Connection: Upgrade, without
+// needing a dedicated handler per protocol.
+func (f *FastReverseProxy) handleUpgrade(rw http.ResponseWriter, req *http.Request, iter *wrappedIterator, accessLog *schema.AccessLogRecord, upgradeType, connectionHeader string) {
+	endpoint, err := selectEndpoint(iter)
+	if err != nil {
+		http.Error(rw, "no endpoints available", http.StatusBadGateway)
+		return
+	}
+	SetupRequest(req, endpoint)
+	req.Header.Set("Upgrade", upgradeType)
+	req.Header.Set("Connection", connectionHeader)
+
+	backendConn, err := net.DialTimeout("tcp", endpoint.CanonicalAddr(), f.endpointTimeout)
+	if err != nil {
+		http.Error(rw, "failed to connect to backend", http.StatusBadGateway)
+		return
+	}
+	defer backendConn.Close()
+
+	req.RequestURI = ""
+	if err := req.Write(backendConn); err != nil {
+		f.logger.Error("upgrade-request-write-failed", err)
+		return
+	}
+
+	backendReader := bufio.NewReader(backendConn)
+	backendResp, err := http.ReadResponse(backendReader, req)
+	if err != nil {
+		f.logger.Error("upgrade-response-read-failed", err)
+		return
+	}
+	accessLog.StatusCode = backendResp.StatusCode
 
-//Until onExitFlushLoop the following is copied from golang release-candidate 1.7 reverse_proxy.go
-func (p *FastReverseProxy) copyResponse(dst io.Writer, src io.Reader) {
-	if wf, ok := dst.(writeFlusher); ok {
-		mlw := &maxLatencyWriter{
-			dst:     wf,
-			latency: 50 * time.Millisecond,
-			done:    make(chan bool),
+	if backendResp.StatusCode != http.StatusSwitchingProtocols {
+		for k, vv := range backendResp.Header {
+			for _, v := range vv {
+				rw.Header().Add(k, v)
+			}
 		}
-		go mlw.flushLoop()
+		rw.WriteHeader(backendResp.StatusCode)
+		io.Copy(rw, backendResp.Body)
+		backendResp.Body.Close()
+		return
+	}
+
+	hijacker, ok := rw.(http.Hijacker)
+	if !ok {
+		http.Error(rw, "webserver doesn't support hijacking", http.StatusInternalServerError)
+		return
+	}
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		f.logger.Error("upgrade-hijack-failed", err)
+		return
+	}
+	defer clientConn.Close()
+
+	// A 101 response has no HTTP body: any bytes of the upgraded stream read
+	// past the headers sit in backendReader. Nil the body out so Write only
+	// serializes the status line and headers, then bridge the raw stream,
+	// buffered bytes first, ourselves below.
+	backendResp.Body = nil
+	if err := backendResp.Write(clientConn); err != nil {
+		f.logger.Error("upgrade-response-write-failed", err)
+		return
+	}
+
+	backendStream := io.Reader(backendConn)
+	if n := backendReader.Buffered(); n > 0 {
+		buffered, _ := backendReader.Peek(n)
+		backendStream = io.MultiReader(bytes.NewReader(buffered), backendConn)
+	}
+
+	clientStream := io.Reader(clientConn)
+	if n := clientBuf.Reader.Buffered(); n > 0 {
+		buffered, _ := clientBuf.Reader.Peek(n)
+		clientStream = io.MultiReader(bytes.NewReader(buffered), clientConn)
+	}
+
+	var bytesIn, bytesOut int64
+	done := make(chan struct{}, 2)
+
+	go func() {
+		bytesIn, _ = io.Copy(backendConn, clientStream)
+		if tcpConn, ok := backendConn.(*net.TCPConn); ok {
+			tcpConn.CloseWrite()
+		}
+		done <- struct{}{}
+	}()
+	go func() {
+		bytesOut, _ = io.Copy(clientConn, backendStream)
+		if tcpConn, ok := clientConn.(*net.TCPConn); ok {
+			tcpConn.CloseWrite()
+		}
+		done <- struct{}{}
+	}()
+
+	<-done
+	<-done
+
+	accessLog.BodyBytesReceived = bytesIn
+	accessLog.BodyBytesSent = bytesOut
+	f.logger.Debug("upgrade-stream-closed", lager.Data{"bytes_to_backend": bytesIn, "bytes_to_client": bytesOut})
+}
+
+// FlushImmediately and NoPeriodicFlush are the two flushLatency sentinel
+// values accepted by WithFlushLatency: flush after every Write, or never
+// flush on a timer at all.
+const (
+	FlushImmediately time.Duration = 0
+	NoPeriodicFlush  time.Duration = -1
+)
+
+// Until onExitFlushLoop the following is copied from golang release-candidate 1.7 reverse_proxy.go
+func (f *FastReverseProxy) copyResponse(dst io.Writer, src io.Reader, backendResp *http.Response) {
+	latency := f.flushLatency
+	if isStreamingResponse(backendResp) {
+		latency = FlushImmediately
+	}
+
+	if wf, ok := dst.(writeFlusher); ok && latency != NoPeriodicFlush {
+		mlw := newMaxLatencyWriter(wf, latency)
 		defer mlw.stop()
+		if latency > 0 {
+			go mlw.flushLoop()
+		}
 		dst = mlw
 	}
 
-	var buf []byte
-	_, err := io.CopyBuffer(dst, src, buf)
-	if err != nil {
+	buf := f.bufferPool.Get()
+	defer f.bufferPool.Put(buf)
+	io.CopyBuffer(dst, src, buf)
+}
+
+// isStreamingResponse reports whether resp should always be flushed after
+// every write regardless of the configured flush latency, e.g. server-sent
+// events or any chunked response.
+func isStreamingResponse(resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+	if resp.Header.Get("Content-Type") == "text/event-stream" {
+		return true
+	}
+	for _, enc := range resp.TransferEncoding {
+		if enc == "chunked" {
+			return true
+		}
 	}
+	return false
 }
 
 type writeFlusher interface {
@@ -348,14 +522,24 @@ type maxLatencyWriter struct {
 	dst     writeFlusher
 	latency time.Duration
 
-	mu   sync.Mutex // protects Write + Flush
-	done chan bool
+	mu     sync.Mutex // protects Write + Flush
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newMaxLatencyWriter(dst writeFlusher, latency time.Duration) *maxLatencyWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &maxLatencyWriter{dst: dst, latency: latency, ctx: ctx, cancel: cancel}
 }
 
 func (m *maxLatencyWriter) Write(p []byte) (int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.dst.Write(p)
+	n, err := m.dst.Write(p)
+	if m.latency == FlushImmediately {
+		m.dst.Flush()
+	}
+	return n, err
 }
 
 func (m *maxLatencyWriter) flushLoop() {
@@ -363,7 +547,7 @@ func (m *maxLatencyWriter) flushLoop() {
 	defer t.Stop()
 	for {
 		select {
-		case <-m.done:
+		case <-m.ctx.Done():
 			if onExitFlushLoop != nil {
 				onExitFlushLoop()
 			}
@@ -376,55 +560,63 @@ func (m *maxLatencyWriter) flushLoop() {
 	}
 }
 
-func (m *maxLatencyWriter) stop() { m.done <- true }
+// stop cancels the flush loop. Using context.Context instead of sending on
+// a channel avoids the previous race where stop() could block sending to
+// done while flushLoop was concurrently holding mu to flush.
+func (m *maxLatencyWriter) stop() { m.cancel() }
 
 // onExitFlushLoop is a callback set by tests to detect the state of the
 // flushLoop() goroutine.
 var onExitFlushLoop func()
 
-// func setupStickySession(responseWriter http.ResponseWriter, backendRespHeaders *fasthttp.ResponseHeader,
-// 	endpoint *route.Endpoint,
-// 	originalEndpointId string,
-// 	secureCookies bool,
-// 	path string) {
-// 	secure := false
-// 	maxAge := 0
-
-// 	// did the endpoint change?
-// 	sticky := originalEndpointId != "" && originalEndpointId != endpoint.PrivateInstanceId
-
-// 	cookieFunc := func(key, value []byte) {
-// 		if string(key) == StickyCookieKey {
-// 			sticky = true
-// 			// TODO: parse resp cookie to get the max age since fhttp does not support this feature
-// 			//	if v.MaxAge < 0 {
-// 			//			maxAge = v.MaxAge
-// 			//		}
-// 			//		secure = v.Secure
-// 			//			break
-// 		}
-// 	}
+// setupStickySession re-establishes session affinity now that the proxy
+// uses net/http and can actually parse Set-Cookie attributes. If the
+// backend set a cookie named StickyCookieKey (the default is
+// JSESSIONID), or routing landed on a different endpoint than the one the
+// request was originally pinned to, emit a VCAP_ID cookie identifying
+// endpoint so subsequent requests route back here via getStickySession.
+// Path/MaxAge/Secure/HttpOnly/SameSite are mirrored from the backend's
+// sticky cookie when it set one; Secure is OR'd with f.secureCookies.
+func (f *FastReverseProxy) setupStickySession(responseWriter http.ResponseWriter, backendResp *http.Response, endpoint *route.Endpoint, originalEndpointId, path string) {
+	// did the endpoint change?
+	sticky := originalEndpointId != "" && originalEndpointId != endpoint.PrivateInstanceId
+
+	var backendCookie *http.Cookie
+	for _, c := range backendResp.Cookies() {
+		if c.Name == StickyCookieKey {
+			sticky = true
+			backendCookie = c
+			break
+		}
+	}
 
-// 	backendRespHeaders.VisitAllCookie(cookieFunc)
-// 	if sticky {
-// 		// right now secure attribute would as equal to the JSESSION ID cookie (if present),
-// 		// but override if set to true in config
-// 		if secureCookies {
-// 			secure = true
-// 		}
+	if !sticky {
+		return
+	}
 
-// 		cookie := &http.Cookie{
-// 			Name:     VcapCookieId,
-// 			Value:    endpoint.PrivateInstanceId,
-// 			Path:     path,
-// 			MaxAge:   maxAge,
-// 			HttpOnly: true,
-// 			Secure:   secure,
-// 		}
+	cookie := &http.Cookie{
+		Name:     VcapCookieId,
+		Value:    endpoint.PrivateInstanceId,
+		Path:     path,
+		HttpOnly: true,
+	}
+	if backendCookie != nil {
+		if backendCookie.Path != "" {
+			cookie.Path = backendCookie.Path
+		}
+		cookie.MaxAge = backendCookie.MaxAge
+		cookie.Secure = backendCookie.Secure
+		cookie.HttpOnly = backendCookie.HttpOnly
+		cookie.SameSite = backendCookie.SameSite
+	}
+	// right now the secure attribute would equal the sticky cookie's (if
+	// present), but override if set to true in config
+	if f.secureCookies {
+		cookie.Secure = true
+	}
 
-// 		http.SetCookie(responseWriter, cookie)
-// 	}
-// }
+	http.SetCookie(responseWriter, cookie)
+}
 
 // func copyRequest(req *http.Request, newReq *http.Request) (io.ReadCloser, error) {
 // 	fmt.Println(req.TransferEncoding)
@@ -489,7 +681,13 @@ var onExitFlushLoop func()
 // 	return closer, nil
 // }
 
-func setupRequest(request *http.Request, endpoint *route.Endpoint) {
+// SetupRequest stamps request with the headers backends rely on for routing
+// context: the application ID being routed to, and an instance identifier
+// (the endpoint's private instance ID, falling back to its address) used to
+// attribute the request to a specific instance. It is exported so other
+// proxy.Builder implementations, like proxy/fast, can shape requests the
+// same way FastReverseProxy does.
+func SetupRequest(request *http.Request, endpoint *route.Endpoint) {
 	request.Header.Set("X-CF-ApplicationID", endpoint.ApplicationId) // why do we need this ?
 	value := endpoint.PrivateInstanceId
 	if value == "" {
@@ -502,6 +700,36 @@ func setupRequest(request *http.Request, endpoint *route.Endpoint) {
 	// }
 }
 
+// SetupForwardedHeaders appends request's client address onto
+// X-Forwarded-For (folding multiple prior hops into one comma-separated
+// header) and sets X-Forwarded-Proto from the request's scheme, unless
+// forceHttps is set or the header was already populated upstream. It is
+// exported so other proxy.Builder implementations, like proxy/fast, can
+// shape requests the same way FastReverseProxy does.
+func SetupForwardedHeaders(request *http.Request, forceHttps bool) {
+	if clientIP, _, err := net.SplitHostPort(request.RemoteAddr); err == nil {
+		// If we aren't the first proxy retain prior
+		// X-Forwarded-For information as a comma+space
+		// separated list and fold multiple headers into one.
+		clientIPKey := clientIP
+		prior := request.Header.Get(xForwardedForKey)
+		if prior != "" {
+			clientIPKey = fmt.Sprintf("%s, %s", prior, clientIP)
+		}
+		request.Header.Set(xForwardedForKey, clientIPKey)
+	}
+
+	if forceHttps {
+		request.Header.Set("X-Forwarded-Proto", "https")
+	} else if request.Header.Get("X-Forwarded-Proto") == "" {
+		scheme := "http"
+		if request.TLS != nil {
+			scheme = "https"
+		}
+		request.Header.Set("X-Forwarded-Proto", scheme)
+	}
+}
+
 func hostWithoutPort(req *http.Request) string {
 	host := req.Host
 
@@ -538,11 +766,19 @@ func getStickySession(request *http.Request) string {
 	return ""
 }
 
-func isWebSocketUpgrade(request *http.Request) bool {
+func isWebSocketUpgrade(upgradeType string) bool {
 	// websocket should be case insensitive per RFC6455 4.2.1
-	return strings.ToLower(upgradeHeader(request)) == "websocket"
+	return strings.ToLower(upgradeType) == "websocket"
+}
+
+func isTcpUpgrade(upgradeType string) bool {
+	return upgradeType == "tcp"
 }
 
-func isTcpUpgrade(request *http.Request) bool {
-	return upgradeHeader(request) == "tcp"
+// isUpgradeRequest reports whether the request is asking for any HTTP/1.1
+// protocol upgrade other than the websocket/tcp cases already handled above
+// (SPDY, HTTP/2 cleartext, gRPC-over-h2c, or anything else a backend is
+// willing to speak over Connection: Upgrade).
+func isUpgradeRequest(upgradeType, connectionHeader string) bool {
+	return upgradeType != "" && strings.Contains(strings.ToLower(connectionHeader), "upgrade")
 }
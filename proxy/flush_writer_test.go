@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestIsStreamingResponseDetectsEventStream verifies a text/event-stream
+// Content-Type is always treated as streaming, regardless of transfer
+// encoding.
+func TestIsStreamingResponseDetectsEventStream(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Content-Type": []string{"text/event-stream"}}}
+	if !isStreamingResponse(resp) {
+		t.Errorf("expected text/event-stream to be reported as streaming")
+	}
+}
+
+// TestIsStreamingResponseDetectsChunked verifies a chunked transfer
+// encoding is reported as streaming even with an unrelated Content-Type.
+func TestIsStreamingResponseDetectsChunked(t *testing.T) {
+	resp := &http.Response{
+		Header:           http.Header{"Content-Type": []string{"application/json"}},
+		TransferEncoding: []string{"chunked"},
+	}
+	if !isStreamingResponse(resp) {
+		t.Errorf("expected chunked transfer-encoding to be reported as streaming")
+	}
+}
+
+// TestIsStreamingResponseNonStreaming verifies a plain response with
+// neither marker, and a nil response, are both reported as non-streaming.
+func TestIsStreamingResponseNonStreaming(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Content-Type": []string{"application/json"}}}
+	if isStreamingResponse(resp) {
+		t.Errorf("expected a plain response to not be reported as streaming")
+	}
+	if isStreamingResponse(nil) {
+		t.Errorf("expected a nil response to not be reported as streaming")
+	}
+}
+
+// TestMaxLatencyWriterStopRaceWithFlushLoop drives flushLoop and a
+// concurrent stop() under the race detector, guarding the
+// context.Context-based cancellation against the prior bug where stop()
+// sent on a done channel that flushLoop might never again select on
+// while it held mu to flush. It also asserts onExitFlushLoop fires once
+// flushLoop has observed cancellation.
+func TestMaxLatencyWriterStopRaceWithFlushLoop(t *testing.T) {
+	rec := httptest.NewRecorder()
+	mlw := newMaxLatencyWriter(rec, time.Millisecond)
+
+	exited := make(chan struct{})
+	var once sync.Once
+	onExitFlushLoop = func() { once.Do(func() { close(exited) }) }
+	defer func() { onExitFlushLoop = nil }()
+
+	go mlw.flushLoop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		mlw.Write([]byte("a"))
+	}()
+	go func() {
+		defer wg.Done()
+		mlw.stop()
+	}()
+	wg.Wait()
+
+	select {
+	case <-exited:
+	case <-time.After(time.Second):
+		t.Fatal("flushLoop did not exit after stop()")
+	}
+}
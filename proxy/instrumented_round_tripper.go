@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/metrics/reporter"
+)
+
+// instrumentedRoundTripper wraps a base RoundTripper to emit Dropsonde-style
+// per-request metrics via reporter.ProxyReporter: how long the round trip to
+// the backend took, and how many response bytes came back.
+type instrumentedRoundTripper struct {
+	next     http.RoundTripper
+	reporter reporter.ProxyReporter
+}
+
+func (t *instrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && t.reporter != nil {
+		resp.Body = &countingReadCloser{ReadCloser: resp.Body, onClose: func(n int64) {
+			t.reporter.CaptureRoutingResponse(resp, time.Since(start), n)
+		}}
+	}
+	return resp, err
+}
+
+// countingReadCloser counts the bytes read from a response body and reports
+// the total once the body is closed, which is the point a response is fully
+// read and accounted for.
+type countingReadCloser struct {
+	io.ReadCloser
+	n       int64
+	onClose func(n int64)
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReadCloser) Close() error {
+	err := c.ReadCloser.Close()
+	c.onClose(c.n)
+	return err
+}
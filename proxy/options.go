@@ -0,0 +1,46 @@
+package proxy
+
+import "time"
+
+// Option configures optional behavior on a FastReverseProxy. Unset options
+// fall back to DefaultRetryPolicy, defaultBodyBufferThreshold,
+// defaultCopyBufferSize, and defaultFlushLatency.
+type Option func(*FastReverseProxy)
+
+// WithRetryPolicy overrides the default retry policy (3 attempts, modest
+// exponential backoff, no status-code-driven retries).
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(f *FastReverseProxy) {
+		f.retryPolicy = policy
+	}
+}
+
+// WithBodyBufferThreshold overrides how many bytes of a request body are
+// buffered in memory so it can be resent on retry. Bodies larger than this
+// are streamed through once, and retries are disabled entirely when that
+// happens, since there is nothing safe left to resend.
+func WithBodyBufferThreshold(maxBytes int64) Option {
+	return func(f *FastReverseProxy) {
+		f.bodyBufferThreshold = maxBytes
+	}
+}
+
+// WithCopyBufferSize overrides the size of the buffers drawn from the pool
+// used to copy response bodies to the client.
+func WithCopyBufferSize(size int) Option {
+	return func(f *FastReverseProxy) {
+		f.bufferPool = NewBufferPool(size)
+	}
+}
+
+// WithFlushLatency overrides how often a streamed response is flushed to
+// the client. FlushImmediately (0) flushes after every Write, which suits
+// SSE/streaming responses; NoPeriodicFlush (-1) disables flushing
+// altogether, which favors throughput. Responses with
+// Content-Type: text/event-stream or Transfer-Encoding: chunked always
+// flush immediately regardless of this setting.
+func WithFlushLatency(latency time.Duration) Option {
+	return func(f *FastReverseProxy) {
+		f.flushLatency = latency
+	}
+}
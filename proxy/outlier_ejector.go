@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var errEndpointEjected = errors.New("endpoint ejected due to consecutive 5xx responses")
+
+// OutlierEjectorSettings configures how many consecutive 5xx responses an
+// endpoint may return before it is temporarily ejected from rotation.
+type OutlierEjectorSettings struct {
+	ConsecutiveFailures int
+	EjectionPeriod      time.Duration
+}
+
+func (s OutlierEjectorSettings) withDefaults() OutlierEjectorSettings {
+	if s.ConsecutiveFailures <= 0 {
+		s.ConsecutiveFailures = 5
+	}
+	if s.EjectionPeriod <= 0 {
+		s.EjectionPeriod = 30 * time.Second
+	}
+	return s
+}
+
+// staleAddrMultiple sets how long an address's bookkeeping is kept around
+// after it was last seen, as a multiple of EjectionPeriod. CF backends churn
+// constantly -- every app restage or scale event retires an old ip:port for
+// good -- so without this, failures/ejectedAt/lastSeen would grow by one
+// entry per address ever seen over the life of a long-running router
+// process.
+const staleAddrMultiple = 10
+
+// outlierEjector tracks consecutive 5xx responses per backend address and
+// refuses requests to an address for EjectionPeriod once it crosses
+// ConsecutiveFailures, letting FastReverseProxy's retry loop fall through to
+// another endpoint in the same pool instead of repeatedly hammering one
+// that's already failing.
+type outlierEjector struct {
+	settings      OutlierEjectorSettings
+	staleAfter    time.Duration
+	sweepInterval time.Duration
+
+	mu        sync.Mutex
+	failures  map[string]int
+	ejectedAt map[string]time.Time
+	lastSeen  map[string]time.Time
+	lastSwept time.Time
+}
+
+func newOutlierEjector(settings OutlierEjectorSettings) *outlierEjector {
+	settings = settings.withDefaults()
+	return &outlierEjector{
+		settings:      settings,
+		staleAfter:    settings.EjectionPeriod * staleAddrMultiple,
+		sweepInterval: settings.EjectionPeriod * staleAddrMultiple,
+		failures:      make(map[string]int),
+		ejectedAt:     make(map[string]time.Time),
+		lastSeen:      make(map[string]time.Time),
+	}
+}
+
+// Ejected reports whether addr is currently ejected from rotation.
+func (e *outlierEjector) Ejected(addr string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	e.lastSeen[addr] = now
+	e.sweepLocked(now)
+
+	ejectedAt, ok := e.ejectedAt[addr]
+	if !ok {
+		return false
+	}
+	if time.Since(ejectedAt) >= e.settings.EjectionPeriod {
+		delete(e.ejectedAt, addr)
+		e.failures[addr] = 0
+		return false
+	}
+	return true
+}
+
+// Report records the outcome of a request to addr, ejecting it once
+// ConsecutiveFailures 5xx responses in a row have been observed.
+func (e *outlierEjector) Report(addr string, statusCode int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	e.lastSeen[addr] = now
+	e.sweepLocked(now)
+
+	if statusCode < http.StatusInternalServerError {
+		e.failures[addr] = 0
+		return
+	}
+	e.failures[addr]++
+	if e.failures[addr] >= e.settings.ConsecutiveFailures {
+		e.ejectedAt[addr] = now
+	}
+}
+
+// sweepLocked drops bookkeeping for addresses that haven't been seen in
+// staleAfter, so the maps don't grow without bound as backend addresses
+// churn over the life of a long-running router process. It only actually
+// scans the maps once per sweepInterval; mu must already be held.
+func (e *outlierEjector) sweepLocked(now time.Time) {
+	if now.Sub(e.lastSwept) < e.sweepInterval {
+		return
+	}
+	e.lastSwept = now
+	for addr, seen := range e.lastSeen {
+		if now.Sub(seen) >= e.staleAfter {
+			delete(e.lastSeen, addr)
+			delete(e.failures, addr)
+			delete(e.ejectedAt, addr)
+		}
+	}
+}
+
+// outlierEjectingRoundTripper refuses requests to an ejected address and
+// feeds completed requests' status codes back into the ejector.
+type outlierEjectingRoundTripper struct {
+	next    http.RoundTripper
+	ejector *outlierEjector
+}
+
+func (t *outlierEjectingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	addr := req.URL.Host
+	if t.ejector.Ejected(addr) {
+		return nil, errEndpointEjected
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err == nil && resp != nil {
+		t.ejector.Report(addr, resp.StatusCode)
+	}
+	return resp, err
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestOutlierEjectorEjectsAfterConsecutiveFailures verifies an address isn't
+// ejected until ConsecutiveFailures 5xx responses in a row have been seen,
+// and that an intervening success resets the streak.
+func TestOutlierEjectorEjectsAfterConsecutiveFailures(t *testing.T) {
+	e := newOutlierEjector(OutlierEjectorSettings{ConsecutiveFailures: 3, EjectionPeriod: time.Minute})
+	addr := "10.0.0.1:8080"
+
+	e.Report(addr, http.StatusBadGateway)
+	e.Report(addr, http.StatusOK)
+	e.Report(addr, http.StatusBadGateway)
+	if e.Ejected(addr) {
+		t.Fatalf("expected addr not ejected: a success should reset the consecutive-failure streak")
+	}
+
+	e.Report(addr, http.StatusBadGateway)
+	e.Report(addr, http.StatusBadGateway)
+	if !e.Ejected(addr) {
+		t.Errorf("expected addr ejected after %d consecutive 5xx responses", 3)
+	}
+}
+
+// TestOutlierEjectorExpiresAfterEjectionPeriod verifies an ejected address
+// becomes available again once EjectionPeriod has elapsed.
+func TestOutlierEjectorExpiresAfterEjectionPeriod(t *testing.T) {
+	e := newOutlierEjector(OutlierEjectorSettings{ConsecutiveFailures: 1, EjectionPeriod: time.Millisecond})
+	addr := "10.0.0.1:8080"
+
+	e.Report(addr, http.StatusServiceUnavailable)
+	if !e.Ejected(addr) {
+		t.Fatalf("expected addr ejected immediately after crossing ConsecutiveFailures")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	if e.Ejected(addr) {
+		t.Errorf("expected ejection to expire after EjectionPeriod")
+	}
+}
+
+// TestOutlierEjectorSweepsStaleAddrs verifies bookkeeping for addresses not
+// seen in staleAfter is evicted, so a long-running router doesn't leak one
+// entry per address ever seen as CF backends churn.
+func TestOutlierEjectorSweepsStaleAddrs(t *testing.T) {
+	e := newOutlierEjector(OutlierEjectorSettings{EjectionPeriod: time.Millisecond})
+	e.staleAfter = time.Millisecond
+	e.sweepInterval = time.Millisecond
+
+	e.Report("10.0.0.1:8080", http.StatusBadGateway)
+	if _, ok := e.failures["10.0.0.1:8080"]; !ok {
+		t.Fatalf("expected failures entry for 10.0.0.1:8080 to be recorded")
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	e.Report("10.0.0.2:8080", http.StatusOK)
+
+	if _, ok := e.failures["10.0.0.1:8080"]; ok {
+		t.Errorf("expected stale entry for 10.0.0.1:8080 to be swept")
+	}
+	if _, ok := e.lastSeen["10.0.0.2:8080"]; !ok {
+		t.Errorf("expected entry for 10.0.0.2:8080 to survive the sweep that created it")
+	}
+}
@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// defaultBodyBufferThreshold is the default cutoff, in bytes, below which a
+// request body is buffered in memory so it can be resent on retry.
+const defaultBodyBufferThreshold = 64 * 1024
+
+// bufferRequestBody buffers up to maxBytes of req.Body into memory so it can
+// be rewound before a retry. If the body turns out to be larger than
+// maxBytes, it is left as a streaming body -- the bytes already read are
+// stitched back onto the front of it -- and buffered is false, meaning the
+// body must not be resent.
+func bufferRequestBody(req *http.Request, maxBytes int64) (buffered bool, rewind func(), err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true, func() {}, nil
+	}
+
+	limited := io.LimitReader(req.Body, maxBytes+1)
+	buf, err := ioutil.ReadAll(limited)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if int64(len(buf)) > maxBytes {
+		req.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(buf), req.Body))
+		return false, func() {}, nil
+	}
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	rewind = func() {
+		req.Body = ioutil.NopCloser(bytes.NewReader(buf))
+	}
+	return true, rewind, nil
+}
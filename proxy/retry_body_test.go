@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+// TestBufferRequestBodyAtThresholdIsBuffered verifies a body exactly
+// maxBytes long is buffered and rewindable, the boundary case for the <=
+// comparison bufferRequestBody makes against maxBytes.
+func TestBufferRequestBodyAtThresholdIsBuffered(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 10)
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+
+	buffered, rewind, err := bufferRequestBody(req, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !buffered {
+		t.Fatalf("expected a body exactly at maxBytes to be buffered")
+	}
+
+	got, _ := ioutil.ReadAll(req.Body)
+	if !bytes.Equal(got, body) {
+		t.Errorf("expected body unchanged, got %q", got)
+	}
+
+	rewind()
+	got, _ = ioutil.ReadAll(req.Body)
+	if !bytes.Equal(got, body) {
+		t.Errorf("expected rewind to restore the full body, got %q", got)
+	}
+}
+
+// TestBufferRequestBodyOverThresholdIsNotBuffered verifies a body one byte
+// over maxBytes is left streaming with buffered=false, and that the bytes
+// already read are stitched back onto the front so nothing is lost.
+func TestBufferRequestBodyOverThresholdIsNotBuffered(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 11)
+	req, _ := http.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+
+	buffered, _, err := bufferRequestBody(req, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buffered {
+		t.Fatalf("expected a body over maxBytes to not be buffered")
+	}
+
+	got, _ := ioutil.ReadAll(req.Body)
+	if !bytes.Equal(got, body) {
+		t.Errorf("expected full body preserved via the stitched-back reader, got %q", got)
+	}
+}
+
+// TestBufferRequestBodyNilBodyIsBuffered verifies a nil/NoBody request is
+// reported as buffered with a no-op rewind, since there's nothing to
+// re-send on retry.
+func TestBufferRequestBodyNilBodyIsBuffered(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+
+	buffered, rewind, err := bufferRequestBody(req, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !buffered {
+		t.Errorf("expected a nil body to be reported as buffered")
+	}
+	rewind()
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides whether a failed attempt to reach a backend should be
+// retried, and for how long to back off before the next attempt. It
+// replaces the old retryableError, which classified every error by whether
+// err.Error() contained the substring "dial" -- a brittle match that also
+// retried non-idempotent requests whose bodies had already been consumed.
+type RetryPolicy struct {
+	MaxRetries           int
+	BackoffBase          time.Duration
+	BackoffMax           time.Duration
+	RetryableStatusCodes map[int]bool
+}
+
+// DefaultRetryPolicy mirrors the behavior FastReverseProxy had before this
+// policy existed: up to 3 attempts, modest backoff, and no status-code-
+// driven retries.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:  3,
+		BackoffBase: 100 * time.Millisecond,
+		BackoffMax:  2 * time.Second,
+	}
+}
+
+// RetryableError reports whether err is the kind of transient failure worth
+// retrying for a request using the given HTTP method. A dial error, a TLS
+// handshake failure, or an endpoint outlierEjector just ejected are always
+// safe to retry: each of them happens before the request is written to the
+// endpoint, so the backend never saw the request. context.DeadlineExceeded
+// and io.EOF, however, can also occur *after* the request was fully written
+// and the backend already acted on it -- a response socket killed by a
+// network blip mid-RoundTrip looks identical to one killed before the
+// backend ever read the request. Resending those is only safe when the
+// method is idempotent, so they're gated on isIdempotentMethod.
+func (p RetryPolicy) RetryableError(err error, method string) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, errEndpointEjected) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var tlsErr tls.RecordHeaderError
+	if errors.As(err, &tlsErr) {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return isIdempotentMethod(method)
+	}
+	if errors.Is(err, io.EOF) {
+		return isIdempotentMethod(method)
+	}
+	return false
+}
+
+// isIdempotentMethod reports whether method is safe to resend against a
+// backend that may have already executed the first attempt: GET, HEAD,
+// OPTIONS, and the other methods RFC 7231 defines as idempotent.
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetryableStatus reports whether statusCode was configured as retryable,
+// e.g. to eject a backend returning 502/503 and try the next endpoint.
+func (p RetryPolicy) RetryableStatus(statusCode int) bool {
+	return p.RetryableStatusCodes[statusCode]
+}
+
+// Backoff returns how long to wait before a retry, where attempt is the
+// number of retries already made (0 before the first retry), using
+// exponential backoff with full jitter capped at BackoffMax.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	base := p.BackoffBase
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	max := p.BackoffMax
+	if max <= 0 {
+		max = 2 * time.Second
+	}
+
+	d := base << uint(attempt)
+	if d <= 0 || d > max {
+		d = max
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
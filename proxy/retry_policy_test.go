@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestRetryableErrorClassification covers the set of errors RetryableError
+// documents as transient, plus a non-transient error that shouldn't be
+// retried. Dial/TLS/ejection errors are retryable regardless of method,
+// since the backend never saw the request; deadline/EOF errors are only
+// retryable for idempotent methods.
+func TestRetryableErrorClassification(t *testing.T) {
+	p := DefaultRetryPolicy()
+
+	retryableAnyMethod := map[string]error{
+		"ejected endpoint":  errEndpointEjected,
+		"dial error":        &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+		"tls record header": tls.RecordHeaderError{},
+	}
+	for name, err := range retryableAnyMethod {
+		if !p.RetryableError(err, http.MethodPost) {
+			t.Errorf("%s: expected retryable for POST", name)
+		}
+	}
+
+	retryableOnlyIdempotent := map[string]error{
+		"context deadline": context.DeadlineExceeded,
+		"io.EOF":           io.EOF,
+		"wrapped io.EOF":   fmt.Errorf("wrapped: %w", io.EOF),
+	}
+	for name, err := range retryableOnlyIdempotent {
+		if !p.RetryableError(err, http.MethodGet) {
+			t.Errorf("%s: expected retryable for GET", name)
+		}
+		if p.RetryableError(err, http.MethodPost) {
+			t.Errorf("%s: expected not retryable for POST", name)
+		}
+	}
+
+	notRetryable := map[string]error{
+		"nil":           nil,
+		"generic error": errors.New("boom"),
+		"read op error": &net.OpError{Op: "read", Err: errors.New("connection reset")},
+	}
+	for name, err := range notRetryable {
+		if p.RetryableError(err, http.MethodGet) {
+			t.Errorf("%s: expected not retryable", name)
+		}
+	}
+}
+
+// TestRetryableStatusHonorsConfiguredCodes verifies only status codes
+// explicitly configured as retryable are reported as such.
+func TestRetryableStatusHonorsConfiguredCodes(t *testing.T) {
+	p := RetryPolicy{RetryableStatusCodes: map[int]bool{502: true, 503: true}}
+
+	if !p.RetryableStatus(502) {
+		t.Errorf("expected 502 retryable")
+	}
+	if p.RetryableStatus(500) {
+		t.Errorf("expected 500 not retryable when not configured")
+	}
+}
+
+// TestBackoffStaysWithinBounds verifies Backoff never exceeds BackoffMax,
+// matching its exponential-with-jitter doc comment.
+func TestBackoffStaysWithinBounds(t *testing.T) {
+	p := RetryPolicy{BackoffBase: 10 * time.Millisecond, BackoffMax: 100 * time.Millisecond}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		d := p.Backoff(attempt)
+		if d < 0 || d > p.BackoffMax {
+			t.Fatalf("attempt %d: backoff %s out of bounds [0, %s]", attempt, d, p.BackoffMax)
+		}
+	}
+}
+
+// TestBackoffAppliesDefaultsWhenUnset verifies Backoff falls back to sane
+// defaults rather than panicking or returning zero when BackoffBase/Max are
+// left unset.
+func TestBackoffAppliesDefaultsWhenUnset(t *testing.T) {
+	p := RetryPolicy{}
+
+	d := p.Backoff(0)
+	if d < 0 || d > 2*time.Second {
+		t.Errorf("expected default-bounded backoff, got %s", d)
+	}
+}
@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/gorouter/metrics/reporter"
+)
+
+// transportKey identifies a cacheable base transport: requests for the same
+// scheme and TLS configuration can share one pool of connections.
+type transportKey struct {
+	scheme        string
+	tlsServerName string
+	tlsConfigHash string
+}
+
+type managedRoundTripper struct {
+	transport *http.Transport
+	rt        http.RoundTripper
+}
+
+// RoundTripperManager builds and caches, per (scheme, TLS server name, TLS
+// config) triple, a single shared http.RoundTripper. Previously
+// FastReverseProxy constructed a fresh http.Client and http.Transport on
+// every retry of every request, with DisableKeepAlives set, which defeated
+// connection reuse entirely. The manager builds each transport once and
+// layers instrumentation, circuit breaking, and outlier ejection on top of
+// it as middleware round trippers.
+type RoundTripperManager struct {
+	reporter        reporter.ProxyReporter
+	dialTimeout     time.Duration
+	breakerSettings CircuitBreakerSettings
+	ejectorSettings OutlierEjectorSettings
+
+	mu     sync.Mutex
+	cached map[transportKey]*managedRoundTripper
+}
+
+// NewRoundTripperManager creates a manager that dials new connections with
+// dialTimeout and applies breakerSettings/ejectorSettings to every
+// transport it builds.
+func NewRoundTripperManager(reporter reporter.ProxyReporter, dialTimeout time.Duration, breakerSettings CircuitBreakerSettings, ejectorSettings OutlierEjectorSettings) *RoundTripperManager {
+	return &RoundTripperManager{
+		reporter:        reporter,
+		dialTimeout:     dialTimeout,
+		breakerSettings: breakerSettings,
+		ejectorSettings: ejectorSettings,
+		cached:          make(map[transportKey]*managedRoundTripper),
+	}
+}
+
+// RoundTripperFor returns the shared RoundTripper for scheme and tlsConfig,
+// building one the first time it's requested.
+func (m *RoundTripperManager) RoundTripperFor(scheme string, tlsConfig *tls.Config) http.RoundTripper {
+	key := transportKey{scheme: scheme}
+	if tlsConfig != nil {
+		key.tlsServerName = tlsConfig.ServerName
+		key.tlsConfigHash = tlsConfigHash(tlsConfig)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if mrt, ok := m.cached[key]; ok {
+		return mrt.rt
+	}
+
+	transport := &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: m.dialTimeout,
+		}).Dial,
+		TLSHandshakeTimeout: m.dialTimeout,
+		TLSClientConfig:     tlsConfig,
+	}
+
+	var rt http.RoundTripper = transport
+	rt = &outlierEjectingRoundTripper{next: rt, ejector: newOutlierEjector(m.ejectorSettings)}
+	rt = &circuitBreakingRoundTripper{next: rt, breakers: newPerEndpointCircuitBreakers(m.breakerSettings)}
+	rt = &instrumentedRoundTripper{next: rt, reporter: m.reporter}
+
+	mrt := &managedRoundTripper{transport: transport, rt: rt}
+	m.cached[key] = mrt
+	return mrt.rt
+}
+
+// Shutdown closes idle connections on every transport the manager has
+// built. It should be called once during graceful drain.
+func (m *RoundTripperManager) Shutdown() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, mrt := range m.cached {
+		mrt.transport.CloseIdleConnections()
+	}
+}
+
+func tlsConfigHash(cfg *tls.Config) string {
+	if cfg == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(cfg.ServerName)
+	if cfg.InsecureSkipVerify {
+		sb.WriteString("|insecure")
+	}
+	for _, proto := range cfg.NextProtos {
+		sb.WriteString("|")
+		sb.WriteString(proto)
+	}
+	return sb.String()
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"code.cloudfoundry.org/gorouter/route"
+)
+
+// TestSetupStickySessionPinsToNewEndpoint covers the case the VCAP_ID cookie
+// exists to handle: a request was pinned to an endpoint that's since been
+// unregistered, routing fell through to a different endpoint in the pool,
+// and subsequent requests need a fresh cookie pinning them to that endpoint
+// instead.
+func TestSetupStickySessionPinsToNewEndpoint(t *testing.T) {
+	f := &FastReverseProxy{}
+	endpoint := &route.Endpoint{PrivateInstanceId: "endpoint-2"}
+	rw := httptest.NewRecorder()
+	backendResp := &http.Response{Header: make(http.Header)}
+
+	f.setupStickySession(rw, backendResp, endpoint, "endpoint-1", "/some/path")
+
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 cookie, got %d: %+v", len(cookies), cookies)
+	}
+	if cookies[0].Name != VcapCookieId {
+		t.Errorf("expected cookie named %s, got %s", VcapCookieId, cookies[0].Name)
+	}
+	if cookies[0].Value != "endpoint-2" {
+		t.Errorf("expected cookie value endpoint-2, got %s", cookies[0].Value)
+	}
+}
+
+// TestSetupStickySessionNoChangeNoCookie covers requests that routed back to
+// the endpoint they were already pinned to: with no backend sticky cookie
+// and no endpoint change, there's nothing new to pin and the client should
+// keep using the VCAP_ID cookie it already has.
+func TestSetupStickySessionNoChangeNoCookie(t *testing.T) {
+	f := &FastReverseProxy{}
+	endpoint := &route.Endpoint{PrivateInstanceId: "endpoint-1"}
+	rw := httptest.NewRecorder()
+	backendResp := &http.Response{Header: make(http.Header)}
+
+	f.setupStickySession(rw, backendResp, endpoint, "endpoint-1", "/some/path")
+
+	if cookies := rw.Result().Cookies(); len(cookies) != 0 {
+		t.Fatalf("expected no cookies, got %+v", cookies)
+	}
+}
+
+// TestSetupStickySessionMirrorsBackendCookieAttributes verifies
+// Path/MaxAge/HttpOnly are copied from the backend's sticky cookie, per
+// setupStickySession's doc comment.
+func TestSetupStickySessionMirrorsBackendCookieAttributes(t *testing.T) {
+	f := &FastReverseProxy{}
+	endpoint := &route.Endpoint{PrivateInstanceId: "endpoint-1"}
+	rw := httptest.NewRecorder()
+	backendResp := &http.Response{Header: make(http.Header)}
+	backendResp.Header.Add("Set-Cookie", (&http.Cookie{
+		Name:     StickyCookieKey,
+		Value:    "sess",
+		Path:     "/app",
+		MaxAge:   60,
+		Secure:   false,
+		HttpOnly: false,
+	}).String())
+
+	f.setupStickySession(rw, backendResp, endpoint, "", "/some/path")
+
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 cookie, got %d: %+v", len(cookies), cookies)
+	}
+	got := cookies[0]
+	if got.Path != "/app" {
+		t.Errorf("expected Path mirrored as /app, got %s", got.Path)
+	}
+	if got.MaxAge != 60 {
+		t.Errorf("expected MaxAge mirrored as 60, got %d", got.MaxAge)
+	}
+	if got.HttpOnly {
+		t.Errorf("expected HttpOnly mirrored as false, got true")
+	}
+}
+
+// TestSetupStickySessionForcesSecureCookies verifies f.secureCookies can
+// upgrade a non-secure backend cookie to Secure, per setupStickySession's
+// doc comment.
+func TestSetupStickySessionForcesSecureCookies(t *testing.T) {
+	f := &FastReverseProxy{secureCookies: true}
+	endpoint := &route.Endpoint{PrivateInstanceId: "endpoint-1"}
+	rw := httptest.NewRecorder()
+	backendResp := &http.Response{Header: make(http.Header)}
+	backendResp.Header.Add("Set-Cookie", (&http.Cookie{
+		Name:   StickyCookieKey,
+		Value:  "sess",
+		Secure: false,
+	}).String())
+
+	f.setupStickySession(rw, backendResp, endpoint, "", "/some/path")
+
+	cookies := rw.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 cookie, got %d: %+v", len(cookies), cookies)
+	}
+	if !cookies[0].Secure {
+		t.Errorf("expected Secure forced true by f.secureCookies, got false")
+	}
+}
+
+// TestGetStickySessionReadsVcapCookie verifies a request carrying both the
+// sticky cookie marker and a VCAP_ID cookie reports the pinned endpoint id,
+// so it can be passed as the pool iterator's initial endpoint.
+func TestGetStickySessionReadsVcapCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: StickyCookieKey, Value: "jsessionid-value"})
+	req.AddCookie(&http.Cookie{Name: VcapCookieId, Value: "endpoint-1"})
+
+	if got := getStickySession(req); got != "endpoint-1" {
+		t.Errorf("expected endpoint-1, got %q", got)
+	}
+}
+
+// TestGetStickySessionWithoutStickyCookieKeyIsIgnored verifies a VCAP_ID
+// cookie alone, without the StickyCookieKey marker, isn't treated as a
+// sticky session -- matching a backend that stopped setting its own sticky
+// cookie.
+func TestGetStickySessionWithoutStickyCookieKeyIsIgnored(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: VcapCookieId, Value: "endpoint-1"})
+
+	if got := getStickySession(req); got != "" {
+		t.Errorf("expected no sticky session without %s cookie, got %q", StickyCookieKey, got)
+	}
+}